@@ -2,14 +2,22 @@
 package app
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/nats-io/nats.go"
 	"github.com/rs/zerolog/log"
 
+	"github.com/todoflow-labs/domain-processor/internal/broker"
 	"github.com/todoflow-labs/domain-processor/internal/config"
 	"github.com/todoflow-labs/domain-processor/internal/processor"
 	"github.com/todoflow-labs/domain-processor/internal/subscriber"
+	"github.com/todoflow-labs/domain-processor/pkg/mtlsnats"
 	"github.com/todoflow-labs/shared-dtos/logging"
 	"github.com/todoflow-labs/shared-dtos/metrics"
 )
@@ -23,38 +31,114 @@ func Run() {
 	logger := logging.New(cfg.LogLevel).With().Str("service", "domain-processor").Logger()
 	logger.Info().Msg("domain-processor starting")
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	metrics.Init(cfg.MetricsAddr)
 	logger.Debug().Msgf("metrics server listening on %s", cfg.MetricsAddr)
 
-	nc, err := nats.Connect(cfg.NATSURL)
+	tp, err := initTracer(ctx, cfg)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("tracer init failed")
+	}
+	defer func() {
+		if err := tp.Shutdown(context.Background()); err != nil {
+			logger.Error().Err(err).Msg("tracer shutdown failed")
+		}
+	}()
+
+	b, closeBroker, err := newBroker(cfg, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("broker init failed")
+	}
+	defer closeBroker()
+
+	h := processor.NewProcessor(b, logger)
+	retry := subscriber.RetryConfig{
+		MaxRetries: cfg.CommandMaxRetries,
+		BaseDelay:  cfg.CommandRetryBaseDelay,
+		MaxDelay:   cfg.CommandRetryMaxDelay,
+	}
+	sub, err := subscriber.SubscribeToCommands(ctx, b, h, logger, retry)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("subscribe failed")
+	}
+
+	logger.Info().Msg("domain-processor is running")
+	<-ctx.Done()
+
+	logger.Info().Msg("shutdown signal received, draining in-flight commands")
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), cfg.NATSAckWait+10*time.Second)
+	defer cancelDrain()
+	if err := sub.Drain(drainCtx); err != nil {
+		logger.Error().Err(err).Msg("drain did not complete before timeout")
+	}
+
+	logger.Info().Msg("domain-processor shut down cleanly")
+}
+
+// newBroker builds the broker.Broker selected by cfg.PubSubBackend
+// (defaulting to nats) and a matching close func for deferred cleanup.
+func newBroker(cfg *config.Config, logger logging.Logger) (broker.Broker, func(), error) {
+	switch cfg.PubSubBackend {
+	case "", "nats":
+		return newNATSBroker(cfg, logger)
+	case "kafka":
+		kb := broker.NewKafkaBroker(cfg.KafkaBrokers, cfg.KafkaGroupID)
+		return kb, func() { _ = kb.Close() }, nil
+	case "mqtt":
+		mb, err := broker.NewMQTTBroker(cfg.MQTTBrokerURL, cfg.MQTTClientID)
+		if err != nil {
+			return nil, nil, err
+		}
+		return mb, func() { _ = mb.Close() }, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown pubsub-backend %q", cfg.PubSubBackend)
+	}
+}
+
+func newNATSBroker(cfg *config.Config, logger logging.Logger) (broker.Broker, func(), error) {
+	natsOpts := []nats.Option{}
+	if cfg.NATSMTLSEnabled {
+		tlsConfig, err := mtlsnats.BuildTLSConfig(cfg.NATSCACert, cfg.NATSClientCert, cfg.NATSClientKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to build NATS mTLS config: %w", err)
+		}
+		natsOpts = append(natsOpts, nats.Secure(tlsConfig))
+	}
+	if cfg.NATSToken != "" {
+		natsOpts = append(natsOpts, nats.Token(cfg.NATSToken))
+	}
+
+	nc, err := nats.Connect(cfg.NATSURL, natsOpts...)
 	if err != nil {
-		logger.Fatal().Err(err).Msg("nats connect failed")
+		return nil, nil, fmt.Errorf("nats connect failed: %w", err)
 	}
-	defer nc.Close()
 
 	js, err := nc.JetStream()
 	if err != nil {
-		logger.Fatal().Err(err).Msg("jetstream init failed")
+		nc.Close()
+		return nil, nil, fmt.Errorf("jetstream init failed: %w", err)
 	}
 
 	for _, stream := range []struct {
-		Name     string
-		Subjects []string
+		Name       string
+		Subjects   []string
+		Duplicates time.Duration
 	}{
-		{"todo_commands", []string{"todo.commands"}},
-		{"todo_events", []string{"todo.events"}},
+		{"todo_commands", []string{"todo.commands"}, 0},
+		{"todo_events", []string{"todo.events"}, cfg.EventDedupeWindow},
+		{"todo_commands_dlq", []string{"todo.commands.dlq"}, 0},
 	} {
 		if _, err := js.AddStream(&nats.StreamConfig{
-			Name: stream.Name, Subjects: stream.Subjects,
+			Name: stream.Name, Subjects: stream.Subjects, Duplicates: stream.Duplicates,
 		}); err != nil && !strings.Contains(err.Error(), "file already in use") {
-			logger.Fatal().Err(err).Msgf("failed to create stream %s", stream.Name)
+			nc.Close()
+			return nil, nil, fmt.Errorf("failed to create stream %s: %w", stream.Name, err)
 		}
 		logger.Debug().Msgf("stream %s ensured", stream.Name)
 	}
 
-	h := processor.NewProcessor(js, logger)
-	subscriber.SubscribeToCommands(js, h, logger)
-
-	logger.Info().Msg("domain-processor is running")
-	select {}
+	b := broker.NewNATSBroker(nc, js, "todo_commands", "domain-processor", cfg.NATSAckWait)
+	return b, func() { _ = b.Close() }, nil
 }
@@ -0,0 +1,53 @@
+// internal/broker/broker.go
+package broker
+
+import "time"
+
+// Message is a single broker delivery. Handlers decide whether the
+// delivery succeeded by calling Ack, or Nak to request redelivery.
+// Headers carries transport metadata (e.g. an injected trace context);
+// backends that don't support headers leave it nil.
+type Message struct {
+	Subject string
+	Data    []byte
+	Headers map[string]string
+	// NumDelivered is the number of times this message has been delivered,
+	// starting at 1. Backends that don't track redelivery counts report 1.
+	NumDelivered uint64
+	// Timestamp is when the backend first received this message, stable
+	// across redeliveries. Backends that can't report the original receive
+	// time (e.g. MQTT) fall back to the current time.
+	Timestamp time.Time
+	Ack       func() error
+	Nak       func() error
+	// NakWithDelay requests redelivery after delay. Nil on backends that
+	// can't schedule redelivery; callers should fall back to Nak.
+	NakWithDelay func(delay time.Duration) error
+}
+
+// Publisher publishes raw bytes to a subject/topic on the underlying bus.
+type Publisher interface {
+	Publish(subject string, data []byte) error
+	// PublishWithHeaders publishes data alongside transport headers (e.g. a
+	// propagated trace context). Backends without header support may ignore
+	// headers rather than fail the publish.
+	PublishWithHeaders(subject string, data []byte, headers map[string]string) error
+}
+
+// Subscriber delivers raw bytes for a subject/topic to handler, leaving
+// ack/nak semantics to the caller via the Message it passes in.
+type Subscriber interface {
+	Subscribe(subject string, handler func(Message)) error
+}
+
+// Broker is the full surface a backend must implement to back
+// domain-processor's command consumption and event publication.
+type Broker interface {
+	Publisher
+	Subscriber
+	// Drain stops accepting new deliveries and, where the backend supports
+	// it, waits for already-dispatched messages to finish before returning.
+	// It does not close the underlying connection.
+	Drain() error
+	Close() error
+}
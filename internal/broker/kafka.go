@@ -0,0 +1,143 @@
+// internal/broker/kafka.go
+package broker
+
+import (
+	"context"
+	"time"
+
+	kafka "github.com/segmentio/kafka-go"
+)
+
+// KafkaBroker adapts a Kafka consumer group / writer pair to the Broker
+// interface. Every domain-processor event is produced to a single topic
+// (todo.events), matching the NATS subject the rest of the codebase uses.
+type KafkaBroker struct {
+	brokers []string
+	groupID string
+	writer  *kafka.Writer
+	readers []*kafka.Reader
+}
+
+// NewKafkaBroker dials no connections eagerly; Publish/Subscribe lazily
+// create the writer/reader they need.
+func NewKafkaBroker(brokers []string, groupID string) *KafkaBroker {
+	return &KafkaBroker{brokers: brokers, groupID: groupID}
+}
+
+func (b *KafkaBroker) Publish(subject string, data []byte) error {
+	return b.PublishWithHeaders(subject, data, nil)
+}
+
+func (b *KafkaBroker) PublishWithHeaders(subject string, data []byte, headers map[string]string) error {
+	if b.writer == nil {
+		// No writer-level Topic: each message carries its own, so one writer
+		// serves every subject (e.g. todo.events and the DLQ).
+		b.writer = &kafka.Writer{
+			Addr:     kafka.TCP(b.brokers...),
+			Balancer: &kafka.LeastBytes{},
+		}
+	}
+	return b.writer.WriteMessages(context.Background(), kafka.Message{
+		Topic:   subject,
+		Value:   data,
+		Headers: mapToKafkaHeaders(headers),
+	})
+}
+
+func (b *KafkaBroker) Subscribe(subject string, handler func(Message)) error {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: b.brokers,
+		GroupID: b.groupID,
+		Topic:   subject,
+	})
+	b.readers = append(b.readers, reader)
+
+	go func() {
+		for {
+			m, err := reader.FetchMessage(context.Background())
+			if err != nil {
+				return
+			}
+			deliver(reader, m, handler)
+		}
+	}()
+
+	return nil
+}
+
+// deliver invokes handler for m, retrying in place when handler signals
+// failure via Nak/NakWithDelay. Kafka consumer groups only redeliver on
+// rebalance, not on demand, so this — rather than the broker — is what
+// makes NumDelivered and the subscriber's MaxRetries/DLQ bookkeeping see
+// real attempts instead of a single delivery per message.
+func deliver(reader *kafka.Reader, m kafka.Message, handler func(Message)) {
+	var numDelivered uint64
+	for {
+		numDelivered++
+		acked := false
+		handler(Message{
+			Subject:      m.Topic,
+			Data:         m.Value,
+			Headers:      kafkaHeadersToMap(m.Headers),
+			NumDelivered: numDelivered,
+			Timestamp:    m.Time,
+			Ack: func() error {
+				acked = true
+				return reader.CommitMessages(context.Background(), m)
+			},
+			Nak: func() error { return nil },
+			NakWithDelay: func(delay time.Duration) error {
+				time.Sleep(delay)
+				return nil
+			},
+		})
+		if acked {
+			return
+		}
+	}
+}
+
+// Drain closes the consumer readers, which stops their fetch loops once any
+// message already handed to the handler finishes processing. The producer
+// writer is left open so in-flight event publishes can still complete.
+func (b *KafkaBroker) Drain() error {
+	var firstErr error
+	for _, r := range b.readers {
+		if err := r.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (b *KafkaBroker) Close() error {
+	firstErr := b.Drain()
+	if b.writer != nil {
+		if err := b.writer.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func mapToKafkaHeaders(headers map[string]string) []kafka.Header {
+	if len(headers) == 0 {
+		return nil
+	}
+	out := make([]kafka.Header, 0, len(headers))
+	for k, v := range headers {
+		out = append(out, kafka.Header{Key: k, Value: []byte(v)})
+	}
+	return out
+}
+
+func kafkaHeadersToMap(headers []kafka.Header) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(headers))
+	for _, h := range headers {
+		m[h.Key] = string(h.Value)
+	}
+	return m
+}
@@ -0,0 +1,52 @@
+//go:build integration
+
+package broker_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	tckafka "github.com/testcontainers/testcontainers-go/modules/kafka"
+
+	"github.com/todoflow-labs/domain-processor/internal/broker"
+)
+
+// TestKafkaBroker_PublishSubscribeRoundTrip exercises KafkaBroker against a
+// real broker started in an ephemeral container, since kafka-go's consumer
+// group behavior isn't realistic to fake in-process. Run with `-tags
+// integration`; it requires a working Docker daemon.
+func TestKafkaBroker_PublishSubscribeRoundTrip(t *testing.T) {
+	ctx := context.Background()
+
+	container, err := tckafka.Run(ctx, "confluentinc/confluent-local:7.6.0")
+	assert.NoError(t, err)
+	defer func() { assert.NoError(t, container.Terminate(ctx)) }()
+
+	brokers, err := container.Brokers(ctx)
+	assert.NoError(t, err)
+
+	b := broker.NewKafkaBroker(brokers, "test-group")
+	defer b.Close()
+
+	received := make(chan broker.Message, 1)
+	err = b.Subscribe("todo.events", func(m broker.Message) {
+		received <- m
+		m.Ack()
+	})
+	assert.NoError(t, err)
+
+	// Give the consumer group time to join before publishing.
+	time.Sleep(2 * time.Second)
+
+	err = b.Publish("todo.events", []byte(`{"hello":"world"}`))
+	assert.NoError(t, err)
+
+	select {
+	case m := <-received:
+		assert.JSONEq(t, `{"hello":"world"}`, string(m.Data))
+	case <-time.After(30 * time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+}
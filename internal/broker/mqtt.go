@@ -0,0 +1,86 @@
+// internal/broker/mqtt.go
+package broker
+
+import (
+	"fmt"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+const mqttQoS = 1
+
+// MQTTBroker adapts an MQTT client to the Broker interface using QoS 1
+// (at-least-once) delivery, matching JetStream's ack-based guarantees as
+// closely as the MQTT 3.1.1 spec allows.
+type MQTTBroker struct {
+	client mqtt.Client
+	topics []string
+}
+
+// NewMQTTBroker connects to an MQTT broker at brokerURL with the given
+// client ID and returns a ready-to-use Broker.
+func NewMQTTBroker(brokerURL, clientID string) (*MQTTBroker, error) {
+	// Disable paho's default of auto-acking every QoS 1 message the instant
+	// it arrives on the wire — without this, the Ack/Nak closures built in
+	// Subscribe are cosmetic, since the broker already considers the message
+	// delivered before handler() ever runs.
+	opts := mqtt.NewClientOptions().AddBroker(brokerURL).SetClientID(clientID).SetAutoAckDisabled(true)
+	client := mqtt.NewClient(opts)
+	if token := client.Connect(); token.Wait() && token.Error() != nil {
+		return nil, fmt.Errorf("mqtt connect: %w", token.Error())
+	}
+	return &MQTTBroker{client: client}, nil
+}
+
+func (b *MQTTBroker) Publish(subject string, data []byte) error {
+	token := b.client.Publish(subject, mqttQoS, false, data)
+	token.Wait()
+	return token.Error()
+}
+
+// PublishWithHeaders ignores headers: MQTT 3.1.1 has no header concept, and
+// paho only implements 3.1.1. Callers that need propagated trace context
+// over MQTT should prefer the nats or kafka backends.
+func (b *MQTTBroker) PublishWithHeaders(subject string, data []byte, _ map[string]string) error {
+	return b.Publish(subject, data)
+}
+
+func (b *MQTTBroker) Subscribe(subject string, handler func(Message)) error {
+	token := b.client.Subscribe(subject, mqttQoS, func(_ mqtt.Client, m mqtt.Message) {
+		handler(Message{
+			Subject:      m.Topic(),
+			Data:         m.Payload(),
+			NumDelivered: 1,
+			// paho doesn't surface the broker's original receive time or a
+			// delivery count, so a QoS 1 redelivery is also reported with
+			// NumDelivered 1 and the time it reached this handler.
+			Timestamp: time.Now(),
+			Ack:       func() error { m.Ack(); return nil },
+			Nak:       func() error { return nil },
+		})
+	})
+	token.Wait()
+	if err := token.Error(); err != nil {
+		return err
+	}
+	b.topics = append(b.topics, subject)
+	return nil
+}
+
+// Drain unsubscribes from every topic so no new messages are delivered,
+// without disconnecting the client.
+func (b *MQTTBroker) Drain() error {
+	if len(b.topics) == 0 {
+		return nil
+	}
+	token := b.client.Unsubscribe(b.topics...)
+	token.Wait()
+	return token.Error()
+}
+
+func (b *MQTTBroker) Close() error {
+	_ = b.Drain()
+	b.client.Disconnect(250)
+	return nil
+}
@@ -0,0 +1,67 @@
+//go:build integration
+
+package broker_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/assert"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/todoflow-labs/domain-processor/internal/broker"
+)
+
+// TestMQTTBroker_PublishSubscribeRoundTrip exercises MQTTBroker against a
+// real eclipse-mosquitto broker started in an ephemeral container, since
+// paho's QoS/ack semantics aren't realistic to fake in-process. Run with
+// `-tags integration`; it requires a working Docker daemon. There's no
+// official testcontainers-go MQTT module, so this uses a generic container
+// request with mosquitto's default anonymous-access config.
+func TestMQTTBroker_PublishSubscribeRoundTrip(t *testing.T) {
+	ctx := context.Background()
+
+	req := testcontainers.ContainerRequest{
+		Image:        "eclipse-mosquitto:2",
+		ExposedPorts: []string{"1883/tcp"},
+		Cmd:          []string{"mosquitto", "-c", "/mosquitto-no-auth.conf"},
+		WaitingFor:   wait.ForListeningPort(nat.Port("1883/tcp")),
+	}
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+	assert.NoError(t, err)
+	defer func() { assert.NoError(t, container.Terminate(ctx)) }()
+
+	host, err := container.Host(ctx)
+	assert.NoError(t, err)
+	port, err := container.MappedPort(ctx, "1883")
+	assert.NoError(t, err)
+
+	brokerURL := fmt.Sprintf("tcp://%s:%s", host, port.Port())
+	b, err := broker.NewMQTTBroker(brokerURL, "test-processor")
+	assert.NoError(t, err)
+	defer b.Close()
+
+	received := make(chan broker.Message, 1)
+	err = b.Subscribe("todo.events", func(m broker.Message) {
+		received <- m
+		m.Ack()
+	})
+	assert.NoError(t, err)
+
+	err = b.Publish("todo.events", []byte(`{"hello":"world"}`))
+	assert.NoError(t, err)
+
+	select {
+	case m := <-received:
+		assert.JSONEq(t, `{"hello":"world"}`, string(m.Data))
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+}
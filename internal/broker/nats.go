@@ -0,0 +1,103 @@
+// internal/broker/nats.go
+package broker
+
+import (
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSBroker adapts a JetStream connection to the Broker interface,
+// preserving today's durable-consumer behavior.
+type NATSBroker struct {
+	nc         *nats.Conn
+	js         nats.JetStreamContext
+	streamName string
+	durable    string
+	ackWait    time.Duration
+	sub        *nats.Subscription
+}
+
+// NewNATSBroker wraps an existing NATS connection/JetStream context.
+// streamName is the JetStream stream to bind the durable consumer to.
+func NewNATSBroker(nc *nats.Conn, js nats.JetStreamContext, streamName, durable string, ackWait time.Duration) *NATSBroker {
+	return &NATSBroker{nc: nc, js: js, streamName: streamName, durable: durable, ackWait: ackWait}
+}
+
+func (b *NATSBroker) Publish(subject string, data []byte) error {
+	_, err := b.js.Publish(subject, data)
+	return err
+}
+
+func (b *NATSBroker) PublishWithHeaders(subject string, data []byte, headers map[string]string) error {
+	msg := &nats.Msg{Subject: subject, Data: data, Header: mapToNATSHeader(headers)}
+	_, err := b.js.PublishMsg(msg)
+	return err
+}
+
+func (b *NATSBroker) Subscribe(subject string, handler func(Message)) error {
+	sub, err := b.js.Subscribe(subject, func(m *nats.Msg) {
+		var numDelivered uint64 = 1
+		timestamp := time.Now()
+		if meta, err := m.Metadata(); err == nil {
+			numDelivered = meta.NumDelivered
+			timestamp = meta.Timestamp
+		}
+		handler(Message{
+			Subject:      m.Subject,
+			Data:         m.Data,
+			Headers:      natsHeaderToMap(m.Header),
+			NumDelivered: numDelivered,
+			Timestamp:    timestamp,
+			Ack:          m.Ack,
+			Nak:          m.Nak,
+			NakWithDelay: m.NakWithDelay,
+		})
+	},
+		nats.Durable(b.durable),
+		nats.Bind(b.streamName, b.durable),
+		nats.ManualAck(),
+		nats.AckWait(b.ackWait),
+	)
+	if err != nil {
+		return err
+	}
+	b.sub = sub
+	return nil
+}
+
+// Drain unsubscribes, letting messages already dispatched to the handler
+// finish, but leaves the underlying connection open.
+func (b *NATSBroker) Drain() error {
+	if b.sub == nil {
+		return nil
+	}
+	return b.sub.Drain()
+}
+
+func (b *NATSBroker) Close() error {
+	b.nc.Close()
+	return nil
+}
+
+func mapToNATSHeader(headers map[string]string) nats.Header {
+	if len(headers) == 0 {
+		return nil
+	}
+	h := make(nats.Header, len(headers))
+	for k, v := range headers {
+		h.Set(k, v)
+	}
+	return h
+}
+
+func natsHeaderToMap(header nats.Header) map[string]string {
+	if len(header) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(header))
+	for k := range header {
+		m[k] = header.Get(k)
+	}
+	return m
+}
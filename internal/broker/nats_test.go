@@ -0,0 +1,72 @@
+package broker_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/todoflow-labs/domain-processor/internal/broker"
+)
+
+func TestNATSBroker_PublishSubscribeRoundTrip(t *testing.T) {
+	opts := &server.Options{
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		Port:      -1,
+		NoLog:     true,
+		NoSigs:    true,
+	}
+	srv, err := server.NewServer(opts)
+	assert.NoError(t, err)
+	go srv.Start()
+	if !srv.ReadyForConnections(10 * time.Second) {
+		t.Fatal("NATS server not ready in time")
+	}
+	defer srv.Shutdown()
+
+	nc, err := nats.Connect(srv.ClientURL())
+	assert.NoError(t, err)
+	defer nc.Close()
+
+	js, err := nc.JetStream()
+	assert.NoError(t, err)
+
+	_, err = js.AddStream(&nats.StreamConfig{
+		Name:     "todo_events",
+		Subjects: []string{"todo.events"},
+	})
+	assert.NoError(t, err)
+
+	// NATSBroker.Subscribe binds to an existing durable push consumer rather
+	// than creating one, so provision it the way a deployment would.
+	_, err = js.AddConsumer("todo_events", &nats.ConsumerConfig{
+		Durable:        "test-broker",
+		DeliverSubject: nats.NewInbox(),
+		FilterSubject:  "todo.events",
+		AckPolicy:      nats.AckExplicitPolicy,
+		AckWait:        30 * time.Second,
+	})
+	assert.NoError(t, err)
+
+	b := broker.NewNATSBroker(nc, js, "todo_events", "test-broker", 30*time.Second)
+
+	received := make(chan broker.Message, 1)
+	err = b.Subscribe("todo.events", func(m broker.Message) {
+		received <- m
+		m.Ack()
+	})
+	assert.NoError(t, err)
+
+	err = b.Publish("todo.events", []byte(`{"hello":"world"}`))
+	assert.NoError(t, err)
+
+	select {
+	case m := <-received:
+		assert.JSONEq(t, `{"hello":"world"}`, string(m.Data))
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for message")
+	}
+}
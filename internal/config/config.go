@@ -3,15 +3,37 @@ package config
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	NATSURL     string `mapstructure:"nats-url"`
-	LogLevel    string `mapstructure:"log-level"`
-	MetricsAddr string `mapstructure:"metrics-addr"`
+	NATSURL         string        `mapstructure:"nats-url"`
+	LogLevel        string        `mapstructure:"log-level"`
+	MetricsAddr     string        `mapstructure:"metrics-addr"`
+	NATSMTLSEnabled bool          `mapstructure:"nats-mtls-enabled"`
+	NATSCACert      string        `mapstructure:"nats-ca-cert"`
+	NATSClientCert  string        `mapstructure:"nats-client-cert"`
+	NATSClientKey   string        `mapstructure:"nats-client-key"`
+	NATSToken       string        `mapstructure:"nats-token"`
+	NATSAckWait     time.Duration `mapstructure:"nats-ack-wait"`
+
+	PubSubBackend string   `mapstructure:"pubsub-backend"`
+	KafkaBrokers  []string `mapstructure:"kafka-brokers"`
+	KafkaGroupID  string   `mapstructure:"kafka-group-id"`
+	MQTTBrokerURL string   `mapstructure:"mqtt-broker-url"`
+	MQTTClientID  string   `mapstructure:"mqtt-client-id"`
+
+	OTELEndpoint    string `mapstructure:"otel-endpoint"`
+	OTELServiceName string `mapstructure:"otel-service-name"`
+
+	CommandMaxRetries     int           `mapstructure:"command-max-retries"`
+	CommandRetryBaseDelay time.Duration `mapstructure:"command-retry-base-delay"`
+	CommandRetryMaxDelay  time.Duration `mapstructure:"command-retry-max-delay"`
+
+	EventDedupeWindow time.Duration `mapstructure:"event-dedupe-window"`
 }
 
 func Load() (*Config, error) {
@@ -23,6 +45,23 @@ func Load() (*Config, error) {
 	pflag.String("database-url", "", "Database connection URL")
 	pflag.String("log-level", "", "Log level")
 	pflag.String("metrics-addr", "", "Metrics listen address")
+	pflag.Bool("nats-mtls-enabled", false, "Enable mutually-authenticated TLS for the NATS connection")
+	pflag.String("nats-ca-cert", "", "Path to the CA certificate used to verify the NATS server")
+	pflag.String("nats-client-cert", "", "Path to the client certificate for NATS mTLS")
+	pflag.String("nats-client-key", "", "Path to the client private key for NATS mTLS")
+	pflag.String("nats-token", "", "NATS auth token, used alongside or instead of mTLS")
+	pflag.Duration("nats-ack-wait", 30*time.Second, "How long JetStream waits for an ack before redelivering a command")
+	pflag.String("pubsub-backend", "nats", "Message broker backend: nats, kafka, or mqtt")
+	pflag.StringSlice("kafka-brokers", nil, "Kafka bootstrap broker addresses")
+	pflag.String("kafka-group-id", "domain-processor", "Kafka consumer group ID")
+	pflag.String("mqtt-broker-url", "", "MQTT broker URL")
+	pflag.String("mqtt-client-id", "domain-processor", "MQTT client ID")
+	pflag.String("otel-endpoint", "", "OTLP gRPC collector endpoint (host:port); tracing is disabled when empty")
+	pflag.String("otel-service-name", "domain-processor", "Service name reported on exported trace spans")
+	pflag.Int("command-max-retries", 5, "Number of redeliveries before a command is routed to the DLQ")
+	pflag.Duration("command-retry-base-delay", time.Second, "Base delay for command redelivery backoff")
+	pflag.Duration("command-retry-max-delay", time.Minute, "Upper bound on command redelivery backoff")
+	pflag.Duration("event-dedupe-window", 2*time.Minute, "JetStream duplicate-message window for the todo_events stream")
 	pflag.Parse()
 
 	// Bind flags
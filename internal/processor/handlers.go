@@ -1,31 +1,71 @@
 package processor
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/todoflow-labs/domain-processor/internal/broker"
 	"github.com/todoflow-labs/shared-dtos/dto"
 	"github.com/todoflow-labs/shared-dtos/logging"
 )
 
+var tracer = otel.Tracer("github.com/todoflow-labs/domain-processor/internal/processor")
+
+type idempotencyKeyCtxKey struct{}
+
+// WithIdempotencyKey attaches an upstream-supplied dedup key (e.g. one read
+// off an incoming command's Nats-Msg-Id header) to ctx, so that a later
+// publishEvent call uses it instead of deriving one from the command fields.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	if key == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, idempotencyKeyCtxKey{}, key)
+}
+
+func idempotencyKeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(idempotencyKeyCtxKey{}).(string)
+	return key, ok
+}
+
+// commandMsgID derives a deterministic JetStream dedup key for a command, so
+// that redelivering the same command publishes at most one event. Callers pass
+// the target todo's ID as discriminant for updates/deletes, and the marshaled
+// command body for a create (whose todo ID isn't minted until after the
+// command is handled). Because the todo ID is per todo, not per command, two
+// updates to the same todo from the same user share a key, and within the
+// stream's duplicate window only the first of them is stored.
+func commandMsgID(discriminant, commandType, userID string) string {
+	sum := sha256.Sum256([]byte(discriminant + commandType + userID))
+	return hex.EncodeToString(sum[:])
+}
+
 type CommandHandler interface {
-	HandleCreate(dto.CreateTodoCommand) error
-	HandleUpdate(dto.UpdateTodoCommand) error
-	HandleDelete(dto.DeleteTodoCommand) error
+	HandleCreate(context.Context, dto.CreateTodoCommand) error
+	HandleUpdate(context.Context, dto.UpdateTodoCommand) error
+	HandleDelete(context.Context, dto.DeleteTodoCommand) error
 }
 
 type Processor struct {
-	js     nats.JetStreamContext
-	logger logging.Logger
+	publisher broker.Publisher
+	logger    logging.Logger
 }
 
-func NewProcessor(js nats.JetStreamContext, logger logging.Logger) *Processor {
-	return &Processor{js: js, logger: logger}
+func NewProcessor(publisher broker.Publisher, logger logging.Logger) *Processor {
+	return &Processor{publisher: publisher, logger: logger}
 }
 
-func (p *Processor) HandleCreate(cmd dto.CreateTodoCommand) error {
+func (p *Processor) HandleCreate(ctx context.Context, cmd dto.CreateTodoCommand) error {
 	evt := dto.TodoCreatedEvent{
 		BaseEvent: dto.BaseEvent{
 			Type:      dto.TodoCreatedEvt,
@@ -40,11 +80,21 @@ func (p *Processor) HandleCreate(cmd dto.CreateTodoCommand) error {
 		Tags:        cmd.Tags,
 	}
 
+	msgID, ok := idempotencyKeyFromContext(ctx)
+	if !ok {
+		// cmd.ID is always empty for a create (the todo ID is minted above,
+		// after the command is received), so fall back to a hash of the
+		// whole command body rather than colliding every create from the
+		// same user onto a single dedup key.
+		body, _ := json.Marshal(cmd)
+		msgID = commandMsgID(string(body), string(dto.CreateTodoCmd), cmd.UserID)
+	}
+
 	p.logger.Debug().Msgf("Emitting TodoCreatedEvent for user %s", cmd.UserID)
-	return p.publishEvent("todo.events", evt)
+	return p.publishEvent(ctx, "todo.events", evt, msgID)
 }
 
-func (p *Processor) HandleUpdate(cmd dto.UpdateTodoCommand) error {
+func (p *Processor) HandleUpdate(ctx context.Context, cmd dto.UpdateTodoCommand) error {
 	evt := dto.TodoUpdatedEvent{
 		BaseEvent: dto.BaseEvent{
 			Type:      dto.TodoUpdatedEvt,
@@ -60,11 +110,16 @@ func (p *Processor) HandleUpdate(cmd dto.UpdateTodoCommand) error {
 		Tags:        derefStringSlice(cmd.Tags),
 	}
 
+	msgID, ok := idempotencyKeyFromContext(ctx)
+	if !ok {
+		msgID = commandMsgID(cmd.ID, string(dto.UpdateTodoCmd), cmd.UserID)
+	}
+
 	p.logger.Debug().Msgf("Emitting TodoUpdatedEvent for todo %s", cmd.ID)
-	return p.publishEvent("todo.events", evt)
+	return p.publishEvent(ctx, "todo.events", evt, msgID)
 }
 
-func (p *Processor) HandleDelete(cmd dto.DeleteTodoCommand) error {
+func (p *Processor) HandleDelete(ctx context.Context, cmd dto.DeleteTodoCommand) error {
 	evt := dto.TodoDeletedEvent{
 		BaseEvent: dto.BaseEvent{
 			Type:      dto.TodoDeletedEvt,
@@ -74,18 +129,48 @@ func (p *Processor) HandleDelete(cmd dto.DeleteTodoCommand) error {
 		},
 	}
 
+	msgID, ok := idempotencyKeyFromContext(ctx)
+	if !ok {
+		msgID = commandMsgID(cmd.ID, string(dto.DeleteTodoCmd), cmd.UserID)
+	}
+
 	p.logger.Debug().Msgf("Emitting TodoDeletedEvent for todo %s", cmd.ID)
-	return p.publishEvent("todo.events", evt)
+	return p.publishEvent(ctx, "todo.events", evt, msgID)
 }
 
-func (p *Processor) publishEvent(subject string, evt any) error {
+func (p *Processor) publishEvent(ctx context.Context, subject string, evt any, msgID string) error {
+	ctx, span := tracer.Start(ctx, "event.publish", trace.WithAttributes(
+		attribute.String("messaging.destination", subject),
+	))
+	defer span.End()
+
+	if err := ctx.Err(); err != nil {
+		p.logger.Warn().Err(err).Str("subject", subject).Msg("publish skipped: context already done")
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
 	data, err := json.Marshal(evt)
 	if err != nil {
 		p.logger.Error().Err(err).Msg("failed to serialize event")
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 		return err
 	}
-	if _, err := p.js.Publish(subject, data); err != nil {
-		p.logger.Error().Err(err).Str("subject", subject).Msg("failed to publish event")
+
+	headers := map[string]string{"Nats-Msg-Id": msgID}
+	propagation.TraceContext{}.Inject(ctx, propagation.MapCarrier(headers))
+
+	logger := p.logger.With().
+		Str("trace_id", span.SpanContext().TraceID().String()).
+		Str("span_id", span.SpanContext().SpanID().String()).
+		Logger()
+
+	if err := p.publisher.PublishWithHeaders(subject, data, headers); err != nil {
+		logger.Error().Err(err).Str("subject", subject).Msg("failed to publish event")
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 		return err
 	}
 	return nil
@@ -6,48 +6,17 @@ import (
 	"testing"
 	"time"
 
-	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/nats-io/nats-server/v2/server"
 	"github.com/nats-io/nats.go"
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/mock"
 
+	"github.com/todoflow-labs/domain-processor/internal/broker"
 	"github.com/todoflow-labs/domain-processor/internal/processor"
 	"github.com/todoflow-labs/shared-dtos/dto"
 	"github.com/todoflow-labs/shared-dtos/logging"
 )
 
-type mockDB struct {
-	mock.Mock
-}
-
-func (m *mockDB) Exec(ctx context.Context, query string, args ...any) (pgconn.CommandTag, error) {
-	callArgs := m.Called(ctx, query, args)
-	return callArgs.Get(0).(pgconn.CommandTag), callArgs.Error(1)
-}
-
-func (m *mockDB) QueryRow(ctx context.Context, query string, args ...any) processor.RowScanner {
-	return m.Called(ctx, query, args).Get(0).(processor.RowScanner)
-}
-
-type mockRow struct {
-	mock.Mock
-}
-
-func (r *mockRow) Scan(dest ...any) error {
-	args := r.Called(dest)
-	if len(dest) == 2 {
-		if id, ok := dest[0].(*string); ok {
-			*id = "todo-id"
-		}
-		if ts, ok := dest[1].(*time.Time); ok {
-			*ts = time.Now()
-		}
-	}
-	return args.Error(0)
-}
-
-func setupEmbeddedNATSServer(t *testing.T) (*server.Server, nats.JetStreamContext, *nats.Conn) {
+func setupEmbeddedNATSServer(t *testing.T) (*server.Server, broker.Publisher, nats.JetStreamContext, *nats.Conn) {
 	t.Helper()
 	opts := &server.Options{
 		JetStream: true,
@@ -71,32 +40,29 @@ func setupEmbeddedNATSServer(t *testing.T) (*server.Server, nats.JetStreamContex
 	assert.NoError(t, err)
 
 	_, err = js.AddStream(&nats.StreamConfig{
-		Name:     "todo_events",
-		Subjects: []string{"todo.events"},
+		Name:       "todo_events",
+		Subjects:   []string{"todo.events"},
+		Duplicates: 2 * time.Minute,
 	})
 	assert.NoError(t, err)
 
-	return srv, js, nc
+	b := broker.NewNATSBroker(nc, js, "todo_events", "test-processor", 30*time.Second)
+	return srv, b, js, nc
 }
 
 func TestHandleCreate_Success(t *testing.T) {
-	db := new(mockDB)
-	row := new(mockRow)
 	logger := logging.New("debug")
-	srv, js, nc := setupEmbeddedNATSServer(t)
+	srv, b, js, nc := setupEmbeddedNATSServer(t)
 	defer srv.Shutdown()
 	defer nc.Close()
 
-	db.On("QueryRow", mock.Anything, mock.AnythingOfType("string"), mock.Anything).Return(row)
-	row.On("Scan", mock.Anything).Return(nil)
-
-	h := processor.NewProcessor(js, db, logger)
+	h := processor.NewProcessor(b, logger)
 	cmd := dto.CreateTodoCommand{
 		BaseCommand: dto.BaseCommand{UserID: "user-1"},
 		Title:       "test todo",
 	}
 
-	err := h.HandleCreate(cmd)
+	err := h.HandleCreate(context.Background(), cmd)
 	assert.NoError(t, err)
 
 	sub, err := js.PullSubscribe("todo.events", "test-create")
@@ -110,21 +76,41 @@ func TestHandleCreate_Success(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, cmd.Title, evt.Title)
 	assert.Equal(t, cmd.UserID, evt.UserID)
+}
+
+func TestHandleCreate_TwoDistinctCreatesFromSameUser_BothPublish(t *testing.T) {
+	logger := logging.New("debug")
+	srv, b, js, nc := setupEmbeddedNATSServer(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	h := processor.NewProcessor(b, logger)
+	first := dto.CreateTodoCommand{
+		BaseCommand: dto.BaseCommand{UserID: "user-1"},
+		Title:       "first todo",
+	}
+	second := dto.CreateTodoCommand{
+		BaseCommand: dto.BaseCommand{UserID: "user-1"},
+		Title:       "second todo",
+	}
 
-	db.AssertExpectations(t)
-	row.AssertExpectations(t)
+	assert.NoError(t, h.HandleCreate(context.Background(), first))
+	assert.NoError(t, h.HandleCreate(context.Background(), second))
+
+	sub, err := js.PullSubscribe("todo.events", "test-create-distinct")
+	assert.NoError(t, err)
+	msgs, err := sub.Fetch(2, nats.MaxWait(time.Second))
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 2, "two distinct creates from the same user must not be deduplicated")
 }
 
 func TestHandleUpdate_Success(t *testing.T) {
-	db := new(mockDB)
 	logger := logging.New("debug")
-	srv, js, nc := setupEmbeddedNATSServer(t)
+	srv, b, js, nc := setupEmbeddedNATSServer(t)
 	defer srv.Shutdown()
 	defer nc.Close()
 
-	db.On("Exec", mock.Anything, mock.AnythingOfType("string"), mock.Anything).Return(pgconn.NewCommandTag("UPDATE 1"), nil)
-
-	h := processor.NewProcessor(js, db, logger)
+	h := processor.NewProcessor(b, logger)
 	title := "Updated Title"
 	completed := true
 	cmd := dto.UpdateTodoCommand{
@@ -136,7 +122,7 @@ func TestHandleUpdate_Success(t *testing.T) {
 		Completed: &completed,
 	}
 
-	err := h.HandleUpdate(cmd)
+	err := h.HandleUpdate(context.Background(), cmd)
 	assert.NoError(t, err)
 
 	sub, err := js.PullSubscribe("todo.events", "test-update")
@@ -150,21 +136,43 @@ func TestHandleUpdate_Success(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, cmd.ID, evt.ID)
 	assert.Equal(t, cmd.UserID, evt.UserID)
+}
+
+func TestHandleUpdate_RedeliveredCommand_DeduplicatesEvent(t *testing.T) {
+	logger := logging.New("debug")
+	srv, b, js, nc := setupEmbeddedNATSServer(t)
+	defer srv.Shutdown()
+	defer nc.Close()
+
+	h := processor.NewProcessor(b, logger)
+	title := "Updated Title"
+	cmd := dto.UpdateTodoCommand{
+		BaseCommand: dto.BaseCommand{
+			ID:     "todo-id",
+			UserID: "user-1",
+		},
+		Title: &title,
+	}
 
-	db.AssertExpectations(t)
+	// Simulate the subscriber redelivering the same command (e.g. after a
+	// nak) by calling the handler twice with identical command fields.
+	assert.NoError(t, h.HandleUpdate(context.Background(), cmd))
+	assert.NoError(t, h.HandleUpdate(context.Background(), cmd))
+
+	sub, err := js.PullSubscribe("todo.events", "test-update-dedupe")
+	assert.NoError(t, err)
+	msgs, err := sub.Fetch(2, nats.MaxWait(time.Second))
+	assert.NoError(t, err)
+	assert.Len(t, msgs, 1, "redelivering the same command should publish at most one event")
 }
 
 func TestHandleDelete_Success(t *testing.T) {
-	db := new(mockDB)
 	logger := logging.New("debug")
-	srv, js, nc := setupEmbeddedNATSServer(t)
+	srv, b, js, nc := setupEmbeddedNATSServer(t)
 	defer srv.Shutdown()
 	defer nc.Close()
 
-	db.On("Exec", mock.Anything, mock.AnythingOfType("string"), mock.Anything).
-		Return(pgconn.NewCommandTag("DELETE 1"), nil)
-
-	h := processor.NewProcessor(js, db, logger)
+	h := processor.NewProcessor(b, logger)
 	cmd := dto.DeleteTodoCommand{
 		BaseCommand: dto.BaseCommand{
 			ID:     "todo-id",
@@ -172,7 +180,7 @@ func TestHandleDelete_Success(t *testing.T) {
 		},
 	}
 
-	err := h.HandleDelete(cmd)
+	err := h.HandleDelete(context.Background(), cmd)
 	assert.NoError(t, err)
 
 	sub, err := js.PullSubscribe("todo.events", "test-delete")
@@ -186,6 +194,4 @@ func TestHandleDelete_Success(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, cmd.ID, evt.ID)
 	assert.Equal(t, cmd.UserID, evt.UserID)
-
-	db.AssertExpectations(t)
 }
@@ -0,0 +1,18 @@
+// internal/subscriber/metrics.go
+package subscriber
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	commandsRetriedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "commands_retried_total",
+		Help: "Number of commands NAK'd for redelivery after a handler error.",
+	})
+	commandsDLQTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "commands_dlq_total",
+		Help: "Number of commands routed to the dead-letter queue.",
+	})
+)
@@ -2,49 +2,173 @@
 package subscriber
 
 import (
+	"context"
 	"encoding/json"
+	"strconv"
+	"sync"
 	"time"
 
-	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/todoflow-labs/domain-processor/internal/broker"
 	"github.com/todoflow-labs/domain-processor/internal/processor"
 	"github.com/todoflow-labs/shared-dtos/dto"
 	"github.com/todoflow-labs/shared-dtos/logging"
 )
 
-func SubscribeToCommands(js nats.JetStreamContext, handler processor.CommandHandler, logger logging.Logger) {
-	_, err := js.Subscribe("todo.commands", func(m *nats.Msg) {
+const dlqSubject = "todo.commands.dlq"
+
+var tracer = otel.Tracer("github.com/todoflow-labs/domain-processor/internal/subscriber")
+
+// RetryConfig bounds how many times a failing command is redelivered
+// before it's routed to the DLQ, and how the backoff between attempts grows.
+type RetryConfig struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// Subscription tracks the in-flight handler calls for a live subscribe so
+// a caller can drain it cleanly during shutdown.
+type Subscription struct {
+	broker broker.Broker
+	wg     sync.WaitGroup
+}
+
+// Drain stops the broker from delivering new commands and waits for
+// handlers already in flight to finish, up to ctx's deadline.
+func (s *Subscription) Drain(ctx context.Context) error {
+	if err := s.broker.Drain(); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func SubscribeToCommands(ctx context.Context, b broker.Broker, handler processor.CommandHandler, logger logging.Logger, retry RetryConfig) (*Subscription, error) {
+	sub := &Subscription{broker: b}
+
+	err := b.Subscribe("todo.commands", func(m broker.Message) {
+		sub.wg.Add(1)
+		defer sub.wg.Done()
+
 		var base dto.BaseCommand
 		if err := json.Unmarshal(m.Data, &base); err != nil {
-			logger.Error().Err(err).Msg("invalid command envelope")
+			logger.Error().Err(err).Msg("invalid command envelope: routing to DLQ")
+			commandsDLQTotal.Inc()
+			deadLetter(b, m, err, logger)
 			m.Ack()
 			return
 		}
+
+		msgCtx := propagation.TraceContext{}.Extract(ctx, propagation.MapCarrier(m.Headers))
+		msgCtx = processor.WithIdempotencyKey(msgCtx, m.Headers["Nats-Msg-Id"])
+		msgCtx, span := tracer.Start(msgCtx, "command.handle", trace.WithAttributes(
+			attribute.String("command.type", string(base.Type)),
+			attribute.String("command.user_id", base.UserID),
+		))
+		defer span.End()
+
+		logger := logger.With().
+			Str("trace_id", span.SpanContext().TraceID().String()).
+			Str("span_id", span.SpanContext().SpanID().String()).
+			Logger()
 		logger.Debug().Msgf("Cmd received: %s", base.Type)
 
+		var handleErr error
 		switch base.Type {
 		case dto.CreateTodoCmd:
 			var cmd dto.CreateTodoCommand
 			_ = json.Unmarshal(m.Data, &cmd)
-			handler.HandleCreate(cmd)
+			handleErr = handler.HandleCreate(msgCtx, cmd)
 		case dto.UpdateTodoCmd:
 			var cmd dto.UpdateTodoCommand
 			_ = json.Unmarshal(m.Data, &cmd)
-			handler.HandleUpdate(cmd)
+			handleErr = handler.HandleUpdate(msgCtx, cmd)
 		case dto.DeleteTodoCmd:
 			var cmd dto.DeleteTodoCommand
 			_ = json.Unmarshal(m.Data, &cmd)
-			handler.HandleDelete(cmd)
+			handleErr = handler.HandleDelete(msgCtx, cmd)
 		default:
 			logger.Warn().Msgf("unknown command type: %s", base.Type)
 		}
-		m.Ack()
-	},
-		nats.Durable("domain-processor"),
-		nats.Bind("todo_commands", "domain-processor"),
-		nats.ManualAck(),
-		nats.AckWait(30*time.Second),
-	)
+
+		if handleErr == nil {
+			m.Ack()
+			return
+		}
+
+		span.RecordError(handleErr)
+		span.SetStatus(codes.Error, handleErr.Error())
+
+		deliveries := m.NumDelivered
+		if deliveries == 0 {
+			deliveries = 1
+		}
+		if int(deliveries) > retry.MaxRetries {
+			logger.Error().Err(handleErr).Uint64("deliveries", deliveries).Msg("max retries exceeded: routing to DLQ")
+			commandsDLQTotal.Inc()
+			deadLetter(b, m, handleErr, logger)
+			m.Ack()
+			return
+		}
+
+		commandsRetriedTotal.Inc()
+		backoff := retryBackoff(retry.BaseDelay, retry.MaxDelay, deliveries)
+		logger.Warn().Err(handleErr).Uint64("deliveries", deliveries).Dur("backoff", backoff).Msg("command handler failed: scheduling redelivery")
+		if m.NakWithDelay != nil {
+			if err := m.NakWithDelay(backoff); err != nil {
+				logger.Error().Err(err).Msg("nak with delay failed")
+			}
+		} else if err := m.Nak(); err != nil {
+			logger.Error().Err(err).Msg("nak failed")
+		}
+	})
 	if err != nil {
-		logger.Fatal().Err(err).Msg("subscribe failed")
+		return nil, err
+	}
+	return sub, nil
+}
+
+// retryBackoff computes min(baseDelay * 2^deliveries, maxDelay).
+func retryBackoff(baseDelay, maxDelay time.Duration, deliveries uint64) time.Duration {
+	delay := baseDelay << deliveries
+	if delay <= 0 || delay > maxDelay {
+		return maxDelay
+	}
+	return delay
+}
+
+// deadLetter republishes m to the DLQ subject with headers recording why it
+// died, then leaves acking the original message to the caller.
+func deadLetter(b broker.Publisher, m broker.Message, cause error, logger logging.Logger) {
+	firstSeen := m.Timestamp
+	if firstSeen.IsZero() {
+		firstSeen = time.Now()
+	}
+
+	headers := map[string]string{
+		"original-subject": m.Subject,
+		"error":            cause.Error(),
+		"retry-count":      strconv.FormatUint(m.NumDelivered, 10),
+		"first-seen-ts":    firstSeen.UTC().Format(time.RFC3339),
+	}
+	if err := b.PublishWithHeaders(dlqSubject, m.Data, headers); err != nil {
+		logger.Error().Err(err).Msg("failed to publish to DLQ")
 	}
 }
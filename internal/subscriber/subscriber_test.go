@@ -0,0 +1,145 @@
+package subscriber_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/todoflow-labs/domain-processor/internal/broker"
+	"github.com/todoflow-labs/domain-processor/internal/subscriber"
+	"github.com/todoflow-labs/shared-dtos/dto"
+	"github.com/todoflow-labs/shared-dtos/logging"
+)
+
+// fakeBroker is a minimal broker.Broker test double that hands the handler
+// registered via Subscribe straight back to the caller, so tests can drive
+// delivery/redelivery by hand instead of standing up a real broker.
+type fakeBroker struct {
+	handler func(broker.Message)
+
+	published []publishedMsg
+}
+
+type publishedMsg struct {
+	subject string
+	data    []byte
+	headers map[string]string
+}
+
+func (f *fakeBroker) Publish(subject string, data []byte) error {
+	return f.PublishWithHeaders(subject, data, nil)
+}
+
+func (f *fakeBroker) PublishWithHeaders(subject string, data []byte, headers map[string]string) error {
+	f.published = append(f.published, publishedMsg{subject: subject, data: data, headers: headers})
+	return nil
+}
+
+func (f *fakeBroker) Subscribe(subject string, handler func(broker.Message)) error {
+	f.handler = handler
+	return nil
+}
+
+func (f *fakeBroker) Drain() error { return nil }
+func (f *fakeBroker) Close() error { return nil }
+
+// fakeHandler is a processor.CommandHandler test double that always fails
+// HandleCreate with handleErr, so tests can exercise the subscriber's
+// retry/DLQ bookkeeping without a real Processor.
+type fakeHandler struct {
+	handleErr error
+	calls     int
+}
+
+func (h *fakeHandler) HandleCreate(context.Context, dto.CreateTodoCommand) error {
+	h.calls++
+	return h.handleErr
+}
+func (h *fakeHandler) HandleUpdate(context.Context, dto.UpdateTodoCommand) error { return nil }
+func (h *fakeHandler) HandleDelete(context.Context, dto.DeleteTodoCommand) error { return nil }
+
+func createCommandEnvelope(t *testing.T) []byte {
+	t.Helper()
+	data, err := json.Marshal(dto.CreateTodoCommand{
+		BaseCommand: dto.BaseCommand{Type: dto.CreateTodoCmd, UserID: "user-1"},
+		Title:       "test todo",
+	})
+	assert.NoError(t, err)
+	return data
+}
+
+// fakeMessage builds a broker.Message with Ack/Nak/NakWithDelay wired up to
+// record what the subscriber called, the way a real NakWithDelay-capable
+// backend (e.g. NATS JetStream) would report delivery attempts.
+func fakeMessage(data []byte, numDelivered uint64) (*broker.Message, *bool, *bool, *time.Duration) {
+	var acked, naked bool
+	var delay time.Duration
+	m := &broker.Message{
+		Data:         data,
+		NumDelivered: numDelivered,
+		Ack:          func() error { acked = true; return nil },
+		Nak:          func() error { naked = true; return nil },
+		NakWithDelay: func(d time.Duration) error { naked = true; delay = d; return nil },
+	}
+	return m, &acked, &naked, &delay
+}
+
+func TestSubscribeToCommands_BackoffGrowsWithDeliveryCount(t *testing.T) {
+	fb := &fakeBroker{}
+	h := &fakeHandler{handleErr: errors.New("transient failure")}
+	retry := subscriber.RetryConfig{MaxRetries: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: 10 * time.Second}
+
+	_, err := subscriber.SubscribeToCommands(context.Background(), fb, h, logging.New("debug"), retry)
+	assert.NoError(t, err)
+
+	data := createCommandEnvelope(t)
+	wantDelays := []time.Duration{200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond}
+	for i, want := range wantDelays {
+		m, acked, naked, delay := fakeMessage(data, uint64(i+1))
+		fb.handler(*m)
+		assert.False(t, *acked)
+		assert.True(t, *naked)
+		assert.Equal(t, want, *delay)
+	}
+}
+
+func TestSubscribeToCommands_MaxRetriesExceeded_RoutesToDLQ(t *testing.T) {
+	fb := &fakeBroker{}
+	h := &fakeHandler{handleErr: errors.New("persistent failure")}
+	retry := subscriber.RetryConfig{MaxRetries: 3, BaseDelay: 10 * time.Millisecond, MaxDelay: time.Second}
+
+	_, err := subscriber.SubscribeToCommands(context.Background(), fb, h, logging.New("debug"), retry)
+	assert.NoError(t, err)
+
+	data := createCommandEnvelope(t)
+	m, acked, naked, _ := fakeMessage(data, uint64(retry.MaxRetries+1))
+	fb.handler(*m)
+
+	assert.True(t, *acked, "a command that exhausts its retries must still be acked off the main stream")
+	assert.False(t, *naked)
+	assert.Len(t, fb.published, 1)
+	assert.Equal(t, "todo.commands.dlq", fb.published[0].subject)
+	assert.Equal(t, "persistent failure", fb.published[0].headers["error"])
+}
+
+func TestSubscribeToCommands_PoisonEnvelope_RoutesStraightToDLQ(t *testing.T) {
+	fb := &fakeBroker{}
+	h := &fakeHandler{}
+	retry := subscriber.RetryConfig{MaxRetries: 5, BaseDelay: 10 * time.Millisecond, MaxDelay: time.Second}
+
+	_, err := subscriber.SubscribeToCommands(context.Background(), fb, h, logging.New("debug"), retry)
+	assert.NoError(t, err)
+
+	m, acked, naked, _ := fakeMessage([]byte("not json"), 1)
+	fb.handler(*m)
+
+	assert.Equal(t, 0, h.calls, "a poison envelope must never reach the command handler")
+	assert.True(t, *acked)
+	assert.False(t, *naked)
+	assert.Len(t, fb.published, 1)
+	assert.Equal(t, "todo.commands.dlq", fb.published[0].subject)
+}
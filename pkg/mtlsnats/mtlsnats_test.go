@@ -0,0 +1,115 @@
+package mtlsnats_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/todoflow-labs/domain-processor/pkg/mtlsnats"
+)
+
+// writeFakeCA generates a self-signed CA and a client certificate/key signed
+// by it, writing all three as PEM files under dir, so tests can substitute a
+// fake CA instead of depending on a real NATS cluster's certificates.
+func writeFakeCA(t *testing.T, dir string) (caPath, certPath, keyPath string) {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	assert.NoError(t, err)
+
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	clientTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "domain-processor"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	clientDER, err := x509.CreateCertificate(rand.Reader, clientTemplate, caTemplate, &clientKey.PublicKey, caKey)
+	assert.NoError(t, err)
+
+	caCert, err := x509.ParseCertificate(caDER)
+	assert.NoError(t, err)
+
+	caPath = writePEM(t, dir, "ca.pem", "CERTIFICATE", caCert.Raw)
+	certPath = writePEM(t, dir, "client.pem", "CERTIFICATE", clientDER)
+	keyDER, err := x509.MarshalECPrivateKey(clientKey)
+	assert.NoError(t, err)
+	keyPath = writePEM(t, dir, "client-key.pem", "EC PRIVATE KEY", keyDER)
+
+	return caPath, certPath, keyPath
+}
+
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	assert.NoError(t, pem.Encode(f, &pem.Block{Type: blockType, Bytes: der}))
+	return path
+}
+
+func TestBuildTLSConfig_Success(t *testing.T) {
+	dir := t.TempDir()
+	caPath, certPath, keyPath := writeFakeCA(t, dir)
+
+	cfg, err := mtlsnats.BuildTLSConfig(caPath, certPath, keyPath)
+	assert.NoError(t, err)
+
+	assert.Equal(t, uint16(tls.VersionTLS12), cfg.MinVersion)
+	assert.Len(t, cfg.Certificates, 1)
+	assert.NotNil(t, cfg.RootCAs)
+}
+
+func TestBuildTLSConfig_MissingCACert(t *testing.T) {
+	dir := t.TempDir()
+	_, certPath, keyPath := writeFakeCA(t, dir)
+
+	_, err := mtlsnats.BuildTLSConfig(filepath.Join(dir, "missing-ca.pem"), certPath, keyPath)
+	assert.Error(t, err)
+}
+
+func TestBuildTLSConfig_InvalidCACert(t *testing.T) {
+	dir := t.TempDir()
+	_, certPath, keyPath := writeFakeCA(t, dir)
+
+	badCAPath := filepath.Join(dir, "bad-ca.pem")
+	assert.NoError(t, os.WriteFile(badCAPath, []byte("not a certificate"), 0o600))
+
+	_, err := mtlsnats.BuildTLSConfig(badCAPath, certPath, keyPath)
+	assert.Error(t, err)
+}
+
+func TestBuildTLSConfig_MismatchedKeyPair(t *testing.T) {
+	dir := t.TempDir()
+	caPath, certPath, _ := writeFakeCA(t, dir)
+	_, _, otherKeyPath := writeFakeCA(t, t.TempDir())
+
+	_, err := mtlsnats.BuildTLSConfig(caPath, certPath, otherKeyPath)
+	assert.Error(t, err)
+}